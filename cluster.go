@@ -0,0 +1,340 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNoClusterAddrs signals an empty seed address list.
+var errNoClusterAddrs = errors.New("redis: no cluster addresses configured")
+
+// clusterSlotCount is the fixed hash slot space size used by Redis Cluster.
+const clusterSlotCount = 16384
+
+// ClusterClient routes commands across a Redis Cluster. It maintains a slot
+// to node map, built from CLUSTER SLOTS, and hands out a per-node Client for
+// any key so callers keep using the normal Client command methods. Exec
+// offers a generic entry point for callers without a typed method, and
+// follows MOVED/ASK redirects on their behalf.
+type ClusterClient struct {
+	timeout, connectTimeout time.Duration
+
+	mutex sync.RWMutex
+	nodes map[string]*Client       // addr -> client, one per known node
+	slots [clusterSlotCount]string // slot -> owning node addr
+}
+
+// NewClusterClient discovers cluster topology from CLUSTER SLOTS, using
+// addrs as seed nodes, and returns a ClusterClient ready for use. Timeout and
+// connectTimeout are passed through to every per-node Client, as documented
+// on NewClient.
+func NewClusterClient(addrs []string, timeout, connectTimeout time.Duration) (*ClusterClient, error) {
+	if len(addrs) == 0 {
+		return nil, errNoClusterAddrs
+	}
+	if connectTimeout == 0 {
+		connectTimeout = time.Second
+	}
+
+	cc := &ClusterClient{
+		timeout:        timeout,
+		connectTimeout: connectTimeout,
+		nodes:          make(map[string]*Client),
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		if err := cc.refreshSlots(addr); err != nil {
+			lastErr = err
+			continue
+		}
+		return cc, nil
+	}
+	return nil, fmt.Errorf("redis: cluster slot discovery failed: %w", lastErr)
+}
+
+// refreshSlots queries seedAddr for CLUSTER SLOTS and rebuilds the slot map.
+func (cc *ClusterClient) refreshSlots(seedAddr string) error {
+	conn, err := net.DialTimeout("tcp", normalizeAddr(seedAddr), cc.connectTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(cc.connectTimeout))
+	if _, err := conn.Write(encodeCommand("CLUSTER", "SLOTS")); err != nil {
+		return err
+	}
+
+	reply, err := readReply(bufio.NewReaderSize(conn, conservativeMSS))
+	if err != nil {
+		return err
+	}
+	ranges, ok := reply.([]interface{})
+	if !ok {
+		return errProtocol
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	return cc.parseSlotRanges(ranges)
+}
+
+// parseSlotRanges walks the CLUSTER SLOTS reply: a list of per-range
+// entries shaped [start, end, [ip, port, id], ...replicas]. start and end
+// are RESP integers, and the master node is the first nested array, whose
+// first two elements are the ip and port bulk strings. The caller must hold
+// cc.mutex.
+func (cc *ClusterClient) parseSlotRanges(ranges []interface{}) error {
+	for _, entry := range ranges {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields) < 3 {
+			continue
+		}
+		start, ok := fields[0].(int64)
+		if !ok {
+			continue
+		}
+		end, ok := fields[1].(int64)
+		if !ok {
+			continue
+		}
+		master, ok := fields[2].([]interface{})
+		if !ok || len(master) < 2 {
+			continue
+		}
+		ip, ok := master[0].([]byte)
+		if !ok {
+			continue
+		}
+		port, ok := master[1].([]byte)
+		if !ok {
+			continue
+		}
+
+		addr := net.JoinHostPort(string(ip), string(port))
+		cc.nodeFor(addr)
+		for slot := start; slot <= end && slot < clusterSlotCount; slot++ {
+			cc.slots[slot] = addr
+		}
+	}
+	return nil
+}
+
+// readReply decodes one complete RESP value from r, recursing into nested
+// arrays as needed. Simple strings are returned as []byte, integers as
+// int64, bulk strings as []byte (nil for a null bulk), and arrays as
+// []interface{} (nil for a null array) of these same kinds. A leading '-'
+// line is returned as a ServerError.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+
+	switch line[0] {
+	case '-':
+		return nil, ServerError(line[1:])
+	case '+':
+		return line[1:], nil
+	case ':':
+		return ParseInt(line[1:]), nil
+	case '$':
+		n := ParseInt(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n := ParseInt(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		array := make([]interface{}, n)
+		for i := range array {
+			v, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = v
+		}
+		return array, nil
+	default:
+		return nil, errProtocol
+	}
+}
+
+// nodeFor returns the Client for addr, dialing a new one if necessary. The
+// caller must hold cc.mutex.
+func (cc *ClusterClient) nodeFor(addr string) *Client {
+	addr = normalizeAddr(addr)
+	if client, ok := cc.nodes[addr]; ok {
+		return client
+	}
+	client := NewClient(addr, cc.timeout, cc.connectTimeout, nil)
+	cc.nodes[addr] = client
+	return client
+}
+
+// ClientForKey returns the Client that owns key's hash slot, so that typed
+// command methods (as defined on Client) can be invoked directly.
+//
+// Unlike Exec, a typed command method called on the returned Client does not
+// follow a MOVED or ASK redirect: after a resharding or failover, a stale
+// slot mapping surfaces as a raw ServerError("MOVED ...") or ("ASK ...")
+// from the command itself, with no retry. Callers that need redirects
+// followed automatically should use Exec instead.
+func (cc *ClusterClient) ClientForKey(key string) *Client {
+	slot := KeySlot(key)
+
+	cc.mutex.RLock()
+	addr := cc.slots[slot]
+	cc.mutex.RUnlock()
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	return cc.nodeFor(addr)
+}
+
+// Exec runs a raw command for key against the Client owning its hash slot,
+// following a single MOVED or ASK redirect should the server return one. It
+// is meant for commands without a typed Client method; prefer ClientForKey
+// directly otherwise.
+func (cc *ClusterClient) Exec(key string, args ...string) ([][]byte, error) {
+	client := cc.ClientForKey(key)
+	reply, err := execOnClient(client, args)
+	if err == nil {
+		return reply, nil
+	}
+
+	se, ok := err.(ServerError)
+	if !ok {
+		return nil, err
+	}
+
+	switch se.Prefix() {
+	case "MOVED":
+		addr, ok := redirectAddr(string(se))
+		if !ok {
+			return nil, err
+		}
+		// A MOVED reply means our slot map is stale, and very likely not
+		// just for this one slot (a resharding or failover moves many slots
+		// at once): refresh the whole map from the node we were redirected
+		// to, rather than patching only KeySlot(key). refreshSlots takes
+		// cc.mutex itself, so it must be called without holding it here.
+		if refreshErr := cc.refreshSlots(addr); refreshErr != nil {
+			cc.mutex.Lock()
+			cc.slots[KeySlot(key)] = normalizeAddr(addr)
+			cc.mutex.Unlock()
+		}
+		return execOnClient(cc.ClientForKey(key), args)
+	case "ASK":
+		addr, ok := redirectAddr(string(se))
+		if !ok {
+			return nil, err
+		}
+		return cc.ask(addr, args)
+	default:
+		return nil, err
+	}
+}
+
+// execOnClient runs one command through client's own pipelined connection —
+// the same managed connection its typed command methods use — instead of a
+// one-off dial per command.
+func execOnClient(client *Client, args []string) ([][]byte, error) {
+	p := client.Pipeline()
+	p.Array(args...)
+	results, err := p.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return results[0].Array, results[0].Err
+}
+
+// ask performs a one-shot ASKING-prefixed command against addr, as required
+// for serving an ASK redirect: ASKING must immediately precede the retried
+// command on the very same connection.
+func (cc *ClusterClient) ask(addr string, args []string) ([][]byte, error) {
+	conn, err := net.DialTimeout("tcp", normalizeAddr(addr), cc.connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if cc.timeout != 0 {
+		conn.SetDeadline(time.Now().Add(cc.timeout))
+	}
+
+	r := bufio.NewReaderSize(conn, conservativeMSS)
+
+	if _, err := conn.Write(encodeCommand("ASKING")); err != nil {
+		return nil, err
+	}
+	if _, err := readArray(r); err != nil {
+		if _, ok := err.(ServerError); !ok {
+			return nil, err
+		}
+	}
+
+	if _, err := conn.Write(encodeCommand(args...)); err != nil {
+		return nil, err
+	}
+	return readArray(r)
+}
+
+// redirectAddr extracts the "host:port" suffix from a MOVED/ASK ServerError,
+// formatted by Redis as "MOVED <slot> <host>:<port>" or "ASK <slot> <host>:<port>".
+func redirectAddr(msg string) (string, bool) {
+	i := strings.LastIndexByte(msg, ' ')
+	if i < 0 {
+		return "", false
+	}
+	return msg[i+1:], true
+}
+
+// KeySlot computes the Redis Cluster hash slot for key, honouring the
+// "{tag}" hash tag convention: when key contains a non-empty {...} segment,
+// only that segment is hashed, so related keys can be colocated.
+func KeySlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return crc16(key) % clusterSlotCount
+}
+
+// crc16 implements the CRC16/XMODEM variant (polynomial 0x1021, initial
+// value 0) used by Redis Cluster for slot hashing. Do not "correct" the
+// initial value to 0xFFFF: that is CRC16/CCITT-FALSE, a different variant
+// that Redis Cluster does not use, and would silently hash every key to the
+// wrong slot.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
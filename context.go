@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// commandOKContext, commandIntegerContext, commandBulkContext and
+// commandArrayContext mirror Client's commandOK/commandInteger/commandBulk/
+// commandArray, but honour ctx: should ctx.Done() fire before the response
+// arrives, the call returns ctx.Err() immediately instead of blocking.
+//
+// The in-flight codec is not simply discarded at that point, since manage()
+// still expects exactly one receive on codec.received for every codec it
+// pushed onto the queue. Instead, the response is drained asynchronously
+// once it (eventually) arrives, or once the connection drops, so the codec
+// pool is not leaked by an abandoned caller.
+//
+// This only frees the caller, though: manage() still drains c.queue
+// strictly in order on the one shared connection, so an abandoned slow
+// command still blocks every later command's response until its reply
+// arrives, or the read deadline fires and the connection is dropped.
+// Cancellation does not unblock the queue.
+//
+// deadline, when non-zero, overrides the Client-wide timeout for this call's
+// write only; the read side still uses the Client-wide timeout, which
+// manage() applies to every queued command alike, as the codec carries no
+// per-call deadline.
+
+func (c *Client) commandOKContext(ctx context.Context, codec *codec, deadline time.Duration) error {
+	codec.resultType = okResult
+
+	if err := c.sendContext(ctx, codec, deadline); err != nil {
+		codecPool.Put(codec)
+		return err
+	}
+	if err := c.awaitContext(ctx, codec); err != nil {
+		return err
+	}
+
+	err := codec.result.err
+	codec.result.err = nil
+	codecPool.Put(codec)
+	return err
+}
+
+func (c *Client) commandIntegerContext(ctx context.Context, codec *codec, deadline time.Duration) (int64, error) {
+	codec.resultType = integerResult
+
+	if err := c.sendContext(ctx, codec, deadline); err != nil {
+		codecPool.Put(codec)
+		return 0, err
+	}
+	if err := c.awaitContext(ctx, codec); err != nil {
+		return 0, err
+	}
+
+	integer, err := codec.result.integer, codec.result.err
+	codec.result.integer, codec.result.err = 0, nil
+	codecPool.Put(codec)
+	return integer, err
+}
+
+func (c *Client) commandBulkContext(ctx context.Context, codec *codec, deadline time.Duration) ([]byte, error) {
+	codec.resultType = bulkResult
+
+	if err := c.sendContext(ctx, codec, deadline); err != nil {
+		codecPool.Put(codec)
+		return nil, err
+	}
+	if err := c.awaitContext(ctx, codec); err != nil {
+		return nil, err
+	}
+
+	bulk, err := codec.result.bulk, codec.result.err
+	codec.result.bulk, codec.result.err = nil, nil
+	codecPool.Put(codec)
+	return bulk, err
+}
+
+func (c *Client) commandArrayContext(ctx context.Context, codec *codec, deadline time.Duration) ([][]byte, error) {
+	codec.resultType = arrayResult
+
+	if err := c.sendContext(ctx, codec, deadline); err != nil {
+		codecPool.Put(codec)
+		return nil, err
+	}
+	if err := c.awaitContext(ctx, codec); err != nil {
+		return nil, err
+	}
+
+	array, err := codec.result.array, codec.result.err
+	codec.result.array, codec.result.err = nil, nil
+	codecPool.Put(codec)
+	return array, err
+}
+
+// sendContext is send, with ctx able to cancel the wait for the write
+// semaphore, and deadline able to override the Client-wide timeout for this
+// call's write deadline only. A zero deadline falls back to c.timeout.
+func (c *Client) sendContext(ctx context.Context, codec *codec, deadline time.Duration) error {
+	var conn net.Conn
+	select {
+	case conn = <-c.writeSem:
+		break // lock acquired
+	case err := <-c.offline:
+		if err == nil { // closed
+			err = ErrTerminated
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	perCall := deadline != 0
+	if !perCall {
+		deadline = c.timeout
+	}
+	if deadline != 0 {
+		conn.SetWriteDeadline(time.Now().Add(deadline))
+	}
+	_, err := conn.Write(codec.buf)
+	if perCall {
+		// Clear the override so it cannot outlive this call on the shared
+		// connection: with a zero Client-wide timeout, a later plain send()
+		// never sets a write deadline at all, and would otherwise inherit
+		// this one, already expired.
+		conn.SetWriteDeadline(time.Time{})
+	}
+	if err != nil {
+		// The write semaphore is not released.
+		c.writeErr <- struct{}{} // does not block
+		return err
+	}
+
+	// await response (in line)
+	c.queue <- codec
+
+	// release lock
+	c.writeSem <- conn
+
+	return nil
+}
+
+// awaitContext waits for codec.received, or for ctx to be done. On
+// cancellation, the eventual response is drained on a separate goroutine so
+// that manage() never blocks on it, and the codec is returned to the pool
+// once that happens.
+func (c *Client) awaitContext(ctx context.Context, codec *codec) error {
+	select {
+	case <-codec.received:
+		return nil
+	case <-ctx.Done():
+		go func() {
+			<-codec.received
+			codec.result.err = nil
+			codec.result.integer = 0
+			codec.result.bulk = nil
+			codec.result.array = nil
+			codecPool.Put(codec)
+		}()
+		return ctx.Err()
+	}
+}
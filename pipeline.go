@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Result holds the decoded reply for one command buffered through a
+// Pipeline. Only the field matching the result type the command was
+// buffered with (OK, Integer, Bulk or Array) is meaningful; Err is set
+// instead whenever the server reported a failure for that command.
+type Result struct {
+	Integer int64
+	Bulk    []byte
+	Array   [][]byte
+	Err     error
+}
+
+// Pipeline buffers command codecs and sends them as a single write once
+// Flush is called, returning their results in submission order.
+//
+// This differs from Client.send, which takes writeSem once per command:
+// Flush holds the semaphore for the whole batch, so the buffered codecs
+// reach the response queue back-to-back and in order, even if other
+// goroutines are submitting commands on the same Client concurrently.
+type Pipeline struct {
+	c      *Client
+	codecs []*codec
+	buf    []byte
+}
+
+// Pipeline returns a new, empty Pipeline bound to c.
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// OK buffers a command whose reply is expected to be a simple status, e.g. "OK".
+func (p *Pipeline) OK(args ...string) {
+	p.add(okResult, args)
+}
+
+// Integer buffers a command whose reply is expected to be a RESP integer.
+func (p *Pipeline) Integer(args ...string) {
+	p.add(integerResult, args)
+}
+
+// Bulk buffers a command whose reply is expected to be a bulk string.
+func (p *Pipeline) Bulk(args ...string) {
+	p.add(bulkResult, args)
+}
+
+// Array buffers a command whose reply is expected to be a multi-bulk array.
+func (p *Pipeline) Array(args ...string) {
+	p.add(arrayResult, args)
+}
+
+func (p *Pipeline) add(result resultType, args []string) {
+	cd := codecPool.Get().(*codec)
+	cd.buf = encodeCommand(args...)
+	cd.resultType = result
+	if cd.received == nil {
+		cd.received = make(chan struct{}, 1)
+	}
+
+	p.buf = append(p.buf, cd.buf...)
+	p.codecs = append(p.codecs, cd)
+}
+
+// Flush sends every buffered command as a single write, and blocks for all
+// of their replies. It returns one Result per command, in submission order.
+// Flush resets the Pipeline, so it is ready for reuse once it returns.
+func (p *Pipeline) Flush() ([]Result, error) {
+	if len(p.codecs) == 0 {
+		return nil, nil
+	}
+	if max := cap(p.c.queue); len(p.codecs) > max {
+		// Flush pushes every codec onto c.queue before releasing writeSem,
+		// to keep the batch contiguous in the response queue. A batch
+		// larger than the queue's capacity could block that push forever:
+		// manage()'s own error path needs writeSem to recover from a fatal
+		// read error, which Flush would still be holding.
+		p.abandon()
+		return nil, fmt.Errorf("redis: pipeline batch of %d commands exceeds queue capacity %d; split it into smaller batches", len(p.codecs), max)
+	}
+
+	conn, err := p.acquire()
+	if err != nil {
+		p.abandon()
+		return nil, err
+	}
+
+	if p.c.timeout != 0 {
+		conn.SetWriteDeadline(time.Now().Add(p.c.timeout))
+	}
+	if _, err := conn.Write(p.buf); err != nil {
+		// The write semaphore is not released, mirroring Client.send.
+		p.c.writeErr <- struct{}{} // does not block
+		p.abandon()
+		return nil, err
+	}
+
+	// await responses (in line), then release the lock
+	for _, cd := range p.codecs {
+		p.c.queue <- cd
+	}
+	p.c.writeSem <- conn
+
+	results := make([]Result, len(p.codecs))
+	for i, cd := range p.codecs {
+		<-cd.received // await response
+		results[i] = Result{
+			Integer: cd.result.integer,
+			Bulk:    cd.result.bulk,
+			Array:   cd.result.array,
+			Err:     cd.result.err,
+		}
+		cd.result.integer, cd.result.bulk, cd.result.array, cd.result.err = 0, nil, nil, nil
+		codecPool.Put(cd)
+	}
+
+	p.reset()
+	return results, nil
+}
+
+func (p *Pipeline) acquire() (net.Conn, error) {
+	select {
+	case conn := <-p.c.writeSem:
+		return conn, nil // lock acquired
+	case err := <-p.c.offline:
+		if err == nil { // closed
+			err = ErrTerminated
+		}
+		return nil, err
+	}
+}
+
+func (p *Pipeline) abandon() {
+	for _, cd := range p.codecs {
+		codecPool.Put(cd)
+	}
+	p.reset()
+}
+
+func (p *Pipeline) reset() {
+	p.codecs = p.codecs[:0]
+	p.buf = p.buf[:0]
+}
+
+// ErrTxAborted is returned by Tx.Do once its retry budget is exhausted
+// because WATCHed keys kept changing before EXEC could complete.
+var ErrTxAborted = errors.New("redis: transaction aborted repeatedly; a WATCHed key kept changing")
+
+// Tx brackets a batch of commands with MULTI/EXEC, optionally guarded by
+// WATCH for optimistic concurrency, so the whole batch either applies
+// atomically or not at all.
+//
+// Commands buffered between MULTI and EXEC receive a "+QUEUED" status
+// reply rather than their usual reply, so Tx queues them with Pipeline.OK
+// regardless of their real result type; the actual results only appear
+// nested inside EXEC's reply array. Because Pipeline's Array decodes a
+// multi-bulk array as bulk strings, Tx.Do is suited to transactions whose
+// member commands themselves reply with bulk strings (GET, HGET, LPOP, ...).
+type Tx struct {
+	c     *Client
+	watch []string
+}
+
+// NewTx returns a transaction helper bound to c.
+func (c *Client) NewTx() *Tx {
+	return &Tx{c: c}
+}
+
+// Watch registers keys for optimistic locking: if any of them changes
+// before EXEC, the server aborts the transaction and Do retries fn.
+func (t *Tx) Watch(keys ...string) *Tx {
+	t.watch = append(t.watch, keys...)
+	return t
+}
+
+// Do runs fn inside MULTI/EXEC, using fn to buffer commands onto the
+// transaction's Pipeline with Pipeline.OK. It retries the whole transaction
+// up to maxRetries times whenever the server aborts it because a WATCHed
+// key changed, and returns ErrTxAborted once that budget is exhausted.
+func (t *Tx) Do(maxRetries int, fn func(p *Pipeline)) ([][]byte, error) {
+	for attempt := 0; ; attempt++ {
+		p := t.c.Pipeline()
+		if len(t.watch) != 0 {
+			p.OK(append([]string{"WATCH"}, t.watch...)...)
+		}
+		p.OK("MULTI")
+		fn(p)
+		p.Array("EXEC")
+
+		results, err := p.Flush()
+		if err != nil {
+			return nil, err
+		}
+
+		exec := results[len(results)-1]
+		if exec.Err != nil {
+			return nil, exec.Err
+		}
+		if exec.Array == nil {
+			// A nil EXEC reply means a WATCHed key changed since WATCH.
+			if attempt >= maxRetries {
+				return nil, ErrTxAborted
+			}
+			continue
+		}
+
+		return exec.Array, nil
+	}
+}
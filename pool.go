@@ -0,0 +1,334 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Default pool settings, used until overridden through Client.SetBlockingPool.
+const (
+	defaultPoolMaxIdle     = 2
+	defaultPoolMaxOpen     = 8
+	defaultPoolIdleTimeout = 5 * time.Minute
+	defaultPoolWaitTimeout = 5 * time.Second
+)
+
+// errPoolWaitTimeout signals that WithBlockingConn gave up waiting for a
+// pooled connection to become available.
+var errPoolWaitTimeout = errors.New("redis: timed out waiting for a pooled connection")
+
+// BlockingConn is the connection handle passed to the function given to
+// Client.WithBlockingConn: a dedicated net.Conn plus a bufio.Reader over it,
+// so callers can issue commands that would otherwise stall Client's single
+// pipelined connection, such as BLPOP, BRPOP, XREAD with BLOCK, SUBSCRIBE
+// or FLUSHDB.
+type BlockingConn struct {
+	net.Conn
+	Reader *bufio.Reader
+}
+
+// PoolOptions configures the pool behind Client.WithBlockingConn.
+type PoolOptions struct {
+	// MinIdle connections are dialed and kept warm right away.
+	MinIdle int
+	// MaxIdle caps how many connections are kept warm between uses.
+	// Surplus connections are closed on return instead of pooled. Zero
+	// defaults to 2.
+	MaxIdle int
+	// MaxOpen caps how many connections the pool hands out concurrently,
+	// checked-out or idle. Zero defaults to 8.
+	MaxOpen int
+	// IdleTimeout closes a connection that has sat idle for longer than
+	// this once it is next checked out. Zero defaults to five minutes.
+	IdleTimeout time.Duration
+	// WaitTimeout bounds how long WithBlockingConn waits for a connection
+	// once MaxOpen is reached. Zero defaults to five seconds.
+	WaitTimeout time.Duration
+}
+
+// pooledConn is a connection held by blockingPool, together with the
+// bufio.Reader layered over it and the time it was last returned idle.
+type pooledConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	idleAt time.Time
+}
+
+// blockingPool is a small pool of dedicated connections for commands that
+// would otherwise block Client's single pipelined connection.
+type blockingPool struct {
+	// client supplies both the dial target and the connection setup (TLS,
+	// AUTH/SELECT/CLIENT SETNAME) that every pooled connection must also go
+	// through, so a pooled connection behaves identically to the one
+	// Client's own manage() goroutine maintains.
+	client *Client
+
+	maxIdle, maxOpen         int
+	idleTimeout, waitTimeout time.Duration
+
+	mutex   sync.Mutex
+	idle    []*pooledConn
+	open    int
+	waiters []chan *pooledConn
+}
+
+// newBlockingPool builds a pool of dedicated connections to client's server,
+// pre-warming it with opts.MinIdle connections.
+func newBlockingPool(client *Client, opts PoolOptions) *blockingPool {
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = defaultPoolMaxIdle
+	}
+	if opts.MaxOpen <= 0 {
+		opts.MaxOpen = defaultPoolMaxOpen
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = defaultPoolIdleTimeout
+	}
+	if opts.WaitTimeout == 0 {
+		opts.WaitTimeout = defaultPoolWaitTimeout
+	}
+	if opts.MinIdle > opts.MaxIdle {
+		opts.MinIdle = opts.MaxIdle
+	}
+
+	p := &blockingPool{
+		client:      client,
+		maxIdle:     opts.MaxIdle,
+		maxOpen:     opts.MaxOpen,
+		idleTimeout: opts.IdleTimeout,
+		waitTimeout: opts.WaitTimeout,
+	}
+
+	for i := 0; i < opts.MinIdle; i++ {
+		pc, err := p.dial()
+		if err != nil {
+			break
+		}
+		p.mutex.Lock()
+		p.open++
+		p.mutex.Unlock()
+		p.put(pc)
+	}
+
+	return p
+}
+
+// dial reuses Client's own dial and handshake, so a pooled connection picks
+// up the same TLS settings and AUTH/SELECT/CLIENT SETNAME handshake as
+// Client's managed connection, rather than a bare, unauthenticated socket.
+func (p *blockingPool) dial() (*pooledConn, error) {
+	conn, err := p.client.dial(p.client.network)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(conn, conservativeMSS)
+	if err := p.client.handshake(conn, r); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &pooledConn{conn: conn, r: r}, nil
+}
+
+// get checks out a connection, dialing a new one if the pool has room, or
+// waiting for one to be returned otherwise. An idle connection that proves
+// stale (past idleTimeout, or failing a PING health check) is discarded and
+// replaced rather than handed out.
+func (p *blockingPool) get() (*pooledConn, error) {
+	for {
+		p.mutex.Lock()
+		if len(p.idle) > 0 {
+			pc := p.idle[len(p.idle)-1]
+			p.idle = p.idle[:len(p.idle)-1]
+			p.mutex.Unlock()
+
+			if p.idleTimeout != 0 && time.Since(pc.idleAt) > p.idleTimeout {
+				p.discard(pc)
+				continue
+			}
+			if err := p.healthCheck(pc); err != nil {
+				p.discard(pc)
+				continue
+			}
+			return pc, nil
+		}
+
+		if p.open < p.maxOpen {
+			p.open++
+			p.mutex.Unlock()
+
+			pc, err := p.dial()
+			if err != nil {
+				p.mutex.Lock()
+				p.open--
+				p.mutex.Unlock()
+				return nil, err
+			}
+			return pc, nil
+		}
+
+		wait := make(chan *pooledConn, 1)
+		p.waiters = append(p.waiters, wait)
+		p.mutex.Unlock()
+
+		timer := time.NewTimer(p.waitTimeout)
+		select {
+		case pc := <-wait:
+			timer.Stop()
+			return pc, nil
+		case <-timer.C:
+			p.removeWaiter(wait)
+			// A put() may have handed off a connection to wait in the
+			// window between the timer firing and removeWaiter taking the
+			// lock; drain it back into the pool instead of leaking it.
+			select {
+			case pc := <-wait:
+				p.put(pc)
+			default:
+			}
+			return nil, errPoolWaitTimeout
+		}
+	}
+}
+
+// removeWaiter drops wait from p.waiters, if it is still there, so a later
+// put does not hand a connection to an abandoned waiter.
+func (p *blockingPool) removeWaiter(wait chan *pooledConn) {
+	p.mutex.Lock()
+	for i, w := range p.waiters {
+		if w == wait {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			break
+		}
+	}
+	p.mutex.Unlock()
+}
+
+// healthCheck PINGs a connection that has been idle, to catch a half-closed
+// socket before handing it back to a caller.
+func (p *blockingPool) healthCheck(pc *pooledConn) error {
+	pc.conn.SetDeadline(time.Now().Add(p.client.connectTimeout))
+	defer pc.conn.SetDeadline(time.Time{})
+
+	if _, err := pc.conn.Write(encodeCommand("PING")); err != nil {
+		return err
+	}
+	_, err := readArray(pc.r)
+	return err
+}
+
+// put returns pc to the pool: straight to a waiter if one is queued,
+// otherwise into the idle set if there is room, or closed as surplus.
+func (p *blockingPool) put(pc *pooledConn) {
+	p.mutex.Lock()
+	if len(p.waiters) > 0 {
+		wait := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		p.mutex.Unlock()
+		wait <- pc
+		return
+	}
+
+	if len(p.idle) >= p.maxIdle {
+		p.open--
+		p.mutex.Unlock()
+		pc.conn.Close()
+		return
+	}
+
+	pc.idleAt = time.Now()
+	p.idle = append(p.idle, pc)
+	p.mutex.Unlock()
+}
+
+// drop closes pc instead of returning it to the pool, used when fn left the
+// connection in a bad state.
+func (p *blockingPool) drop(pc *pooledConn) {
+	p.mutex.Lock()
+	p.open--
+	p.mutex.Unlock()
+	pc.conn.Close()
+}
+
+// discard is drop for a connection found stale inside get, which has
+// already been removed from p.idle.
+func (p *blockingPool) discard(pc *pooledConn) {
+	p.mutex.Lock()
+	p.open--
+	p.mutex.Unlock()
+	pc.conn.Close()
+}
+
+// closeIdle closes every connection currently idle in the pool, used when a
+// pool is replaced via SetBlockingPool.
+func (p *blockingPool) closeIdle() {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.open -= len(idle)
+	p.mutex.Unlock()
+
+	for _, pc := range idle {
+		pc.conn.Close()
+	}
+}
+
+// SetBlockingPool configures the pool behind WithBlockingConn. Calling it
+// replaces any pool already in place, closing that pool's idle connections;
+// connections currently checked out finish normally and are then discarded
+// rather than returned to the replaced pool.
+func (c *Client) SetBlockingPool(opts PoolOptions) {
+	pool := newBlockingPool(c, opts)
+
+	c.poolMutex.Lock()
+	old := c.pool
+	c.pool = pool
+	c.poolMutex.Unlock()
+
+	if old != nil {
+		old.closeIdle()
+	}
+}
+
+// WithBlockingConn checks out a dedicated connection from the pool behind
+// Client (created lazily with default PoolOptions if SetBlockingPool was
+// never called), runs fn with it, and returns the connection to the pool
+// once fn returns. Use it for commands that would otherwise occupy (and
+// thereby stall) Client's single pipelined connection, such as BLPOP,
+// BRPOP, XREAD with BLOCK, SUBSCRIBE or FLUSHDB.
+//
+// A connection for which fn returns an error is closed instead of being
+// returned to the pool, since fn's error may reflect a broken connection.
+func (c *Client) WithBlockingConn(fn func(conn *BlockingConn) error) error {
+	c.poolMutex.Lock()
+	pool := c.pool
+	if pool == nil {
+		pool = newBlockingPool(c, PoolOptions{})
+		c.pool = pool
+	}
+	c.poolMutex.Unlock()
+
+	pc, err := pool.get()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&BlockingConn{Conn: pc.conn, Reader: pc.r}); err != nil {
+		pool.drop(pc)
+		return err
+	}
+
+	c.poolMutex.Lock()
+	replaced := c.pool != pool
+	c.poolMutex.Unlock()
+	if replaced {
+		// SetBlockingPool swapped pools while fn ran.
+		pool.drop(pc)
+		return nil
+	}
+
+	pool.put(pc)
+	return nil
+}
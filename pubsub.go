@@ -0,0 +1,382 @@
+package redis
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Message is a single Pub/Sub delivery.
+type Message struct {
+	// Channel is the channel the message was published on.
+	Channel string
+	// Pattern is the subscribed pattern that matched Channel. Empty unless
+	// the message arrived through a PSubscribe registration.
+	Pattern string
+	// Payload is the message body.
+	Payload []byte
+}
+
+// PubSub maintains a dedicated connection for Redis publish/subscribe.
+// Unlike Client, which multiplexes a single connection for request/response
+// traffic under a per-command read deadline, PubSub blocks on reads
+// indefinitely, since a message may arrive at any time. Use NewPubSub
+// instead of Client for SUBSCRIBE, UNSUBSCRIBE, PSUBSCRIBE and PUNSUBSCRIBE.
+//
+// Subscriptions survive reconnects: PubSub remembers the channels and
+// patterns it was asked for, and reinstates them automatically once a new
+// connection is established, so a network blip does not lose delivery.
+type PubSub struct {
+	// Addr is the normalized server address in use. This field is read-only.
+	Addr string
+
+	// Messages receives each delivered publication.
+	Messages chan Message
+	// Errors receives ServerError frames from the server (e.g. a rejected
+	// SUBSCRIBE) without tearing down the subscription.
+	Errors chan error
+
+	connectTimeout time.Duration
+	keepAlive      time.Duration
+
+	mutex    sync.Mutex
+	channels map[string]bool
+	patterns map[string]bool
+
+	commands chan []byte
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// NewPubSub launches a managed publish/subscribe connection to a server
+// address: a host:port, defaulting like NewClient, or an absolute Unix
+// domain socket path. Connection URIs (redis://, rediss://, unix://) are not
+// parsed, so no TLS, AUTH or SELECT is applied.
+//
+// ConnectTimeout limits the duration for connection establishment, including
+// reconnects. Zero defaults to one second.
+//
+// KeepAlive sets the interval for keepalive PING commands, sent so that
+// intermediate proxies and firewalls do not consider the idle connection
+// abandoned. Zero defaults to thirty seconds.
+func NewPubSub(addr string, connectTimeout, keepAlive time.Duration) *PubSub {
+	addr = normalizeAddr(addr)
+	if connectTimeout == 0 {
+		connectTimeout = time.Second
+	}
+	if keepAlive == 0 {
+		keepAlive = 30 * time.Second
+	}
+
+	p := &PubSub{
+		Addr:           addr,
+		Messages:       make(chan Message, queueSizeTCP),
+		Errors:         make(chan error, 1),
+		connectTimeout: connectTimeout,
+		keepAlive:      keepAlive,
+		channels:       make(map[string]bool),
+		patterns:       make(map[string]bool),
+		commands:       make(chan []byte),
+		quit:           make(chan struct{}, 1),
+		done:           make(chan struct{}),
+	}
+
+	go p.manage()
+
+	return p
+}
+
+// Close stops the subscription and releases the connection. Further use of
+// p after Close causes undefined behaviour.
+func (p *PubSub) Close() {
+	select {
+	case p.quit <- struct{}{}:
+		break // signal queued
+	default:
+		break // pending signal
+	}
+
+	<-p.done
+}
+
+// Subscribe registers one or more channels for delivery on Messages.
+func (p *PubSub) Subscribe(channels ...string) error {
+	return p.register("SUBSCRIBE", channels, p.channels, true)
+}
+
+// Unsubscribe deregisters one or more channels previously passed to Subscribe.
+func (p *PubSub) Unsubscribe(channels ...string) error {
+	return p.register("UNSUBSCRIBE", channels, p.channels, false)
+}
+
+// PSubscribe registers one or more glob patterns for delivery on Messages.
+func (p *PubSub) PSubscribe(patterns ...string) error {
+	return p.register("PSUBSCRIBE", patterns, p.patterns, true)
+}
+
+// PUnsubscribe deregisters one or more patterns previously passed to PSubscribe.
+func (p *PubSub) PUnsubscribe(patterns ...string) error {
+	return p.register("PUNSUBSCRIBE", patterns, p.patterns, false)
+}
+
+// register updates the bookkeeping set, used to resubscribe after a
+// reconnect, and forwards the command to the connection in use.
+func (p *PubSub) register(command string, names []string, set map[string]bool, add bool) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	p.mutex.Lock()
+	for _, name := range names {
+		if add {
+			set[name] = true
+		} else {
+			delete(set, name)
+		}
+	}
+	p.mutex.Unlock()
+
+	select {
+	case p.commands <- encodeCommand(append([]string{command}, names...)...):
+		return nil
+	case <-p.done:
+		return ErrTerminated
+	}
+}
+
+func (p *PubSub) manage() {
+	defer close(p.done)
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		default:
+			break
+		}
+
+		network := "tcp"
+		if isUnixAddr(p.Addr) {
+			network = "unix"
+		}
+		conn, err := net.DialTimeout(network, p.Addr, p.connectTimeout)
+		if err != nil {
+			select {
+			case p.Errors <- err:
+				break
+			default:
+				break
+			}
+			select {
+			case <-time.After(reconnectDelay):
+				continue
+			case <-p.quit:
+				return
+			}
+		}
+
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetNoDelay(false)
+			tcp.SetLinger(0)
+		}
+
+		if !p.resubscribe(conn) {
+			conn.Close()
+			continue
+		}
+
+		readErr := make(chan error, 1)
+		go p.readLoop(conn, readErr)
+
+		reconnect := p.writeLoop(conn, readErr)
+		conn.Close()
+		if !reconnect {
+			return
+		}
+	}
+}
+
+// resubscribe reinstates the channels and patterns registered before a
+// (re)connect, so the subscriptions carry over. Messages published while no
+// connection was up are not delivered.
+func (p *PubSub) resubscribe(conn net.Conn) bool {
+	p.mutex.Lock()
+	channels := make([]string, 0, len(p.channels))
+	for ch := range p.channels {
+		channels = append(channels, ch)
+	}
+	patterns := make([]string, 0, len(p.patterns))
+	for pat := range p.patterns {
+		patterns = append(patterns, pat)
+	}
+	p.mutex.Unlock()
+
+	if len(channels) != 0 {
+		if _, err := conn.Write(encodeCommand(append([]string{"SUBSCRIBE"}, channels...)...)); err != nil {
+			return false
+		}
+	}
+	if len(patterns) != 0 {
+		if _, err := conn.Write(encodeCommand(append([]string{"PSUBSCRIBE"}, patterns...)...)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeLoop serializes command submission and keepalive pings onto conn. It
+// returns whether manage should dial a replacement connection.
+func (p *PubSub) writeLoop(conn net.Conn, readErr chan error) bool {
+	ping := time.NewTicker(p.keepAlive)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-p.quit:
+			return false
+		case err := <-readErr:
+			select {
+			case p.Errors <- err:
+				break
+			default:
+				break
+			}
+			return true
+		case cmd := <-p.commands:
+			if _, err := conn.Write(cmd); err != nil {
+				return true
+			}
+		case <-ping.C:
+			if _, err := conn.Write(encodeCommand("PING")); err != nil {
+				return true
+			}
+		}
+	}
+}
+
+// readLoop blocks on conn indefinitely, decoding push-type RESP frames and
+// dispatching them to Messages. ServerError frames are surfaced on Errors
+// without ending the loop; any other read error is reported on errc so
+// manage can reconnect.
+func (p *PubSub) readLoop(conn net.Conn, errc chan<- error) {
+	r := bufio.NewReaderSize(conn, conservativeMSS)
+	for {
+		frame, err := readArray(r)
+		if err != nil {
+			if se, ok := err.(ServerError); ok {
+				select {
+				case p.Errors <- se:
+					break
+				default:
+					break
+				}
+				continue
+			}
+			errc <- err
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+
+		switch string(frame[0]) {
+		case "message":
+			if len(frame) == 3 {
+				p.Messages <- Message{Channel: string(frame[1]), Payload: frame[2]}
+			}
+		case "pmessage":
+			if len(frame) == 4 {
+				p.Messages <- Message{Pattern: string(frame[1]), Channel: string(frame[2]), Payload: frame[3]}
+			}
+		case "subscribe", "unsubscribe", "psubscribe", "punsubscribe", "pong":
+			continue // subscription bookkeeping/keepalive ack; nothing to deliver
+		}
+	}
+}
+
+// encodeCommand renders args as a RESP array of bulk strings.
+func encodeCommand(args ...string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, a := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(a)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, a...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readArray reads one push-type RESP frame: a multi-bulk array of bulk
+// strings. A leading '-' line is returned as a ServerError instead, and a
+// leading '+' or ':' line (keepalive acknowledgements) yields a nil frame.
+func readArray(r *bufio.Reader) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+
+	switch line[0] {
+	case '-':
+		return nil, ServerError(line[1:])
+	case '+', ':':
+		return nil, nil
+	case '*':
+		break
+	default:
+		return nil, errProtocol
+	}
+
+	n := ParseInt(line[1:])
+	if n < 0 {
+		return nil, nil
+	}
+
+	frame := make([][]byte, n)
+	for i := range frame {
+		bulk, err := readBulk(r)
+		if err != nil {
+			return nil, err
+		}
+		frame[i] = bulk
+	}
+	return frame, nil
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	return line[:len(line)-2], nil // strip trailing CRLF
+}
+
+func readBulk(r *bufio.Reader) ([]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return nil, errProtocol
+	}
+
+	n := ParseInt(line[1:])
+	if n < 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -6,10 +6,15 @@ package redis
 
 import (
 	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -114,6 +119,16 @@ type Client struct {
 
 	timeout, connectTimeout time.Duration
 
+	// Dial network ("tcp" or "unix") and, when set, TLS parameters and the
+	// AUTH/SELECT/CLIENT SETNAME handshake extracted from a connection URI.
+	network    string
+	useTLS     bool
+	tlsConfig  *tls.Config
+	username   string
+	password   string
+	db         int
+	clientName string
+
 	// Commands lock the semaphore to enqueue the response handler.
 	writeSem chan net.Conn
 	// Fatal write error submission keeps the semaphore locked.
@@ -127,6 +142,15 @@ type Client struct {
 
 	// Terminate request signal for manage().
 	quit chan struct{}
+
+	// Receives a replacement address, forcing a reconnect, e.g. after a
+	// Sentinel failover. Buffered by one; a pending rebind is superseded by
+	// a newer one.
+	rebindAddr chan string
+
+	// Lazily initialized pool backing WithBlockingConn.
+	poolMutex sync.Mutex
+	pool      *blockingPool
 }
 
 // NewClient launches a managed connection to a server address.
@@ -135,6 +159,15 @@ type Client struct {
 // absolute file path (e.g. "/var/run/redis.sock") to use Unix
 // domain sockets.
 //
+// Addr also accepts a full connection URI: "redis://[user:pass@]host:port/db"
+// for a plain TCP connection, "rediss://..." for TLS (configured through
+// tlsConfig; a nil tlsConfig uses crypto/tls defaults), or
+// "unix:///path?db=N" for a Unix domain socket. When a URI carries
+// credentials, a database index or a "clientName" query parameter, NewClient
+// issues AUTH (or `AUTH user pass` for Redis 6 ACLs), SELECT and CLIENT
+// SETNAME during the connection handshake, before any queued command is
+// released to the server.
+//
 // Timeout limits the command duration. Expiry causes a reconnect,
 // to prevent stale connections. Timeout is disabled with zero.
 //
@@ -142,26 +175,39 @@ type Client struct {
 // including reconnects. Once expired, commands receive the timeout
 // error until the connection restores. Client methods block during
 // connect. Zero defaults to one second.
-func NewClient(addr string, timeout, connectTimeout time.Duration) *Client {
-	addr = normalizeAddr(addr)
+func NewClient(addr string, timeout, connectTimeout time.Duration, tlsConfig *tls.Config) *Client {
+	cfg, err := parseAddr(addr)
+	if err != nil {
+		// NewClient reports no error; an invalid address surfaces the same
+		// way an unreachable one would, through commands failing to connect.
+		cfg = addrConfig{network: "tcp", target: normalizeAddr(addr)}
+	}
 	if connectTimeout == 0 {
 		connectTimeout = time.Second
 	}
 	queueSize := queueSizeTCP
-	if isUnixAddr(addr) {
+	if cfg.network == "unix" {
 		queueSize = queueSizeUnix
 	}
 
 	c := &Client{
-		Addr:           addr,
+		Addr:           cfg.target,
 		timeout:        timeout,
 		connectTimeout: connectTimeout,
-
-		writeSem: make(chan net.Conn, 1), // one shared instance
-		writeErr: make(chan struct{}, 1), // may not block
-		queue:    make(chan *codec, queueSize),
-		offline:  make(chan error),
-		quit:     make(chan struct{}, 1),
+		network:        cfg.network,
+		useTLS:         cfg.useTLS,
+		tlsConfig:      tlsConfig,
+		username:       cfg.username,
+		password:       cfg.password,
+		db:             cfg.db,
+		clientName:     cfg.clientName,
+
+		writeSem:   make(chan net.Conn, 1), // one shared instance
+		writeErr:   make(chan struct{}, 1), // may not block
+		queue:      make(chan *codec, queueSize),
+		offline:    make(chan error),
+		quit:       make(chan struct{}, 1),
+		rebindAddr: make(chan string, 1),
 	}
 
 	go c.manage()
@@ -169,6 +215,75 @@ func NewClient(addr string, timeout, connectTimeout time.Duration) *Client {
 	return c
 }
 
+// addrConfig is the result of parsing a NewClient address: either a bare
+// host:port/path, or the components extracted from a connection URI.
+type addrConfig struct {
+	network    string // "tcp" or "unix"
+	target     string // dial target: normalized host:port or unix path
+	useTLS     bool
+	username   string
+	password   string
+	db         int
+	clientName string
+}
+
+// parseAddr interprets addr as a bare host:port / unix path, preserving
+// NewClient's historical behaviour, or as a redis://, rediss:// or unix://
+// connection URI.
+func parseAddr(addr string) (addrConfig, error) {
+	switch {
+	case strings.HasPrefix(addr, "redis://"), strings.HasPrefix(addr, "rediss://"):
+		u, err := url.Parse(addr)
+		if err != nil {
+			return addrConfig{}, fmt.Errorf("redis: invalid connection URI: %w", err)
+		}
+
+		cfg := addrConfig{
+			network: "tcp",
+			target:  normalizeAddr(u.Host),
+			useTLS:  u.Scheme == "rediss",
+		}
+		if u.User != nil {
+			cfg.username = u.User.Username()
+			cfg.password, _ = u.User.Password()
+		}
+		if db := strings.Trim(u.Path, "/"); db != "" {
+			n, err := strconv.Atoi(db)
+			if err != nil {
+				return addrConfig{}, fmt.Errorf("redis: invalid database index %q", db)
+			}
+			cfg.db = n
+		}
+		cfg.clientName = u.Query().Get("clientName")
+		return cfg, nil
+
+	case strings.HasPrefix(addr, "unix://"):
+		u, err := url.Parse(addr)
+		if err != nil {
+			return addrConfig{}, fmt.Errorf("redis: invalid connection URI: %w", err)
+		}
+
+		cfg := addrConfig{network: "unix", target: filepath.Clean(u.Path)}
+		if db := u.Query().Get("db"); db != "" {
+			n, err := strconv.Atoi(db)
+			if err != nil {
+				return addrConfig{}, fmt.Errorf("redis: invalid database index %q", db)
+			}
+			cfg.db = n
+		}
+		cfg.clientName = u.Query().Get("clientName")
+		return cfg, nil
+
+	default:
+		target := normalizeAddr(addr)
+		network := "tcp"
+		if isUnixAddr(target) {
+			network = "unix"
+		}
+		return addrConfig{network: network, target: target}, nil
+	}
+}
+
 // Terminate stops all Client routines, and closes the network connection.
 // Command are rejected with ErrTerminated after return.
 func (c *Client) Terminate() {
@@ -185,6 +300,133 @@ func (c *Client) Terminate() {
 	}
 }
 
+// rebind swaps the target address and forces a reconnect. It is used by
+// SentinelClient on failover.
+func (c *Client) rebind(addr string) {
+	addr = normalizeAddr(addr)
+	for {
+		select {
+		case c.rebindAddr <- addr:
+			return // signal queued
+		default:
+			break // pending signal
+		}
+		// replace the pending address with the newer one
+		select {
+		case <-c.rebindAddr:
+			break
+		default:
+			break
+		}
+	}
+}
+
+// awaitReconnect offers err to blocked command submissions until either one
+// accepts it or the reconnect delay expires.
+func (c *Client) awaitReconnect(err error) {
+	delay := time.NewTimer(reconnectDelay)
+	for {
+		select {
+		case c.offline <- err:
+			continue // unblocked a command request
+		case <-delay.C:
+			break
+		}
+		break
+	}
+}
+
+// dial connects to c.Addr, applying TCP tuning and, when c.useTLS is set,
+// wrapping the connection in a TLS client using c.tlsConfig.
+func (c *Client) dial(network string) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, c.Addr, c.connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetNoDelay(false)
+		tcp.SetLinger(0)
+	}
+
+	if !c.useTLS {
+		return conn, nil
+	}
+
+	tlsConfig := c.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		// A caller-supplied tlsConfig (or the default above) left ServerName
+		// unset: without it, crypto/tls has no hostname to verify the
+		// server's certificate against. Default it to the configured host,
+		// cloning first so a caller-supplied *tls.Config shared across
+		// Clients is never mutated in place.
+		host, _, err := net.SplitHostPort(c.Addr)
+		if err != nil {
+			host = c.Addr
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(c.connectTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// handshake issues AUTH, SELECT and CLIENT SETNAME as configured through the
+// connection URI, before the connection is handed to the command queue.
+func (c *Client) handshake(conn net.Conn, r *bufio.Reader) error {
+	if c.password == "" && c.db == 0 && c.clientName == "" {
+		return nil
+	}
+
+	conn.SetDeadline(time.Now().Add(c.connectTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if c.password != "" {
+		var cmd []byte
+		if c.username != "" {
+			cmd = encodeCommand("AUTH", c.username, c.password)
+		} else {
+			cmd = encodeCommand("AUTH", c.password)
+		}
+		if err := simpleCommand(conn, r, cmd); err != nil {
+			return fmt.Errorf("redis: AUTH during handshake: %w", err)
+		}
+	}
+	if c.db != 0 {
+		cmd := encodeCommand("SELECT", strconv.Itoa(c.db))
+		if err := simpleCommand(conn, r, cmd); err != nil {
+			return fmt.Errorf("redis: SELECT during handshake: %w", err)
+		}
+	}
+	if c.clientName != "" {
+		cmd := encodeCommand("CLIENT", "SETNAME", c.clientName)
+		if err := simpleCommand(conn, r, cmd); err != nil {
+			return fmt.Errorf("redis: CLIENT SETNAME during handshake: %w", err)
+		}
+	}
+	return nil
+}
+
+// simpleCommand writes cmd and consumes its reply, surfacing only a
+// ServerError or connection failure; simple-string and integer replies
+// (e.g. "+OK") are otherwise discarded.
+func simpleCommand(conn net.Conn, r *bufio.Reader, cmd []byte) error {
+	if _, err := conn.Write(cmd); err != nil {
+		return err
+	}
+	_, err := readArray(r)
+	return err
+}
+
 func (c *Client) manage() {
 	defer close(c.offline) // causes ErrTerminate
 
@@ -197,35 +439,23 @@ func (c *Client) manage() {
 		}
 
 		// connect
-		network := "tcp"
-		if isUnixAddr(c.Addr) {
-			network = "unix"
-		}
-		conn, err := net.DialTimeout(network, c.Addr, c.connectTimeout)
+		network := c.network
+		conn, err := c.dial(network)
 		if err != nil {
-			delay := time.NewTimer(reconnectDelay)
-			for {
-				select {
-				case c.offline <- err:
-					continue // unblocked a command request
-				case <-delay.C:
-					break
-				}
-				break
-			}
+			c.awaitReconnect(err)
 			continue
 		}
 
-		// TCP parameter tuning
-		if tcp, ok := conn.(*net.TCPConn); ok {
-			tcp.SetNoDelay(false)
-			tcp.SetLinger(0)
+		r := bufio.NewReaderSize(conn, conservativeMSS)
+		if err := c.handshake(conn, r); err != nil {
+			conn.Close()
+			c.awaitReconnect(err)
+			continue
 		}
 
 		// Release the command submission instance.
 		c.writeSem <- conn
 
-		r := bufio.NewReaderSize(conn, conservativeMSS)
 		for {
 			select {
 			case <-c.quit:
@@ -249,6 +479,14 @@ func (c *Client) manage() {
 				}
 			case <-c.writeErr:
 				break // fatal write error
+			case addr := <-c.rebindAddr:
+				select {
+				case <-c.writeSem:
+					break // semaphore hijack
+				case <-c.writeErr:
+					break // error already detected
+				}
+				c.Addr = addr
 			}
 			break
 		}
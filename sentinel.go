@@ -0,0 +1,149 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// errNoSentinels signals an empty sentinel address list.
+var errNoSentinels = errors.New("redis: no sentinel addresses configured")
+
+// SentinelClient provides command execution against a Redis master whose
+// address is discovered and tracked through one or more Sentinel processes.
+// It embeds *Client, so all of its command methods apply directly; only the
+// address behind them changes across failovers.
+type SentinelClient struct {
+	*Client
+
+	masterName              string
+	sentinelAddrs           []string
+	timeout, connectTimeout time.Duration
+
+	mutex sync.Mutex
+}
+
+// NewSentinelClient resolves the current address for masterName through the
+// given Sentinel processes, launches a Client for it, and keeps the Client
+// pinned to the master by subscribing to Sentinel's "+switch-master" channel.
+// Timeout and connectTimeout are passed through to the underlying Client, as
+// documented on NewClient.
+func NewSentinelClient(masterName string, sentinelAddrs []string, timeout, connectTimeout time.Duration) (*SentinelClient, error) {
+	if len(sentinelAddrs) == 0 {
+		return nil, errNoSentinels
+	}
+	if connectTimeout == 0 {
+		connectTimeout = time.Second
+	}
+
+	sc := &SentinelClient{
+		masterName:     masterName,
+		sentinelAddrs:  sentinelAddrs,
+		timeout:        timeout,
+		connectTimeout: connectTimeout,
+	}
+
+	addr, err := sc.queryMaster()
+	if err != nil {
+		return nil, err
+	}
+	sc.Client = NewClient(addr, timeout, connectTimeout, nil)
+
+	go sc.watch()
+
+	return sc, nil
+}
+
+// queryMaster asks each configured Sentinel in turn for the current address
+// of masterName, returning the first successful answer.
+func (sc *SentinelClient) queryMaster() (string, error) {
+	var lastErr error
+	for _, sentinelAddr := range sc.sentinelAddrs {
+		addr, err := sc.askSentinel(sentinelAddr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return addr, nil
+	}
+	return "", fmt.Errorf("redis: sentinel lookup for master %q failed: %w", sc.masterName, lastErr)
+}
+
+func (sc *SentinelClient) askSentinel(sentinelAddr string) (string, error) {
+	conn, err := net.DialTimeout("tcp", normalizeAddr(sentinelAddr), sc.connectTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(sc.connectTimeout))
+	cmd := encodeCommand("SENTINEL", "get-master-addr-by-name", sc.masterName)
+	if _, err := conn.Write(cmd); err != nil {
+		return "", err
+	}
+
+	reply, err := readArray(bufio.NewReaderSize(conn, conservativeMSS))
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("redis: sentinel returned no address for master %q", sc.masterName)
+	}
+	return net.JoinHostPort(string(reply[0]), string(reply[1])), nil
+}
+
+// watch subscribes to Sentinel's failover notifications and rebinds the
+// Client whenever the master address changes, for as long as the
+// SentinelClient is in use. Notifications come from the
+// first configured sentinel only: PubSub reconnects to that same sentinel
+// after a connection loss, Messages is never closed, and so the other
+// sentinels are not tried.
+func (sc *SentinelClient) watch() {
+	for i := 0; ; i = (i + 1) % len(sc.sentinelAddrs) {
+		pubsub := NewPubSub(sc.sentinelAddrs[i], sc.connectTimeout, 30*time.Second)
+		if err := pubsub.Subscribe("+switch-master"); err != nil {
+			pubsub.Close()
+			time.Sleep(reconnectDelay)
+			continue
+		}
+
+		for msg := range pubsub.Messages {
+			fields := splitSpaces(string(msg.Payload))
+			// Payload: "<master name> <old ip> <old port> <new ip> <new port>"
+			if len(fields) != 5 || fields[0] != sc.masterName {
+				continue
+			}
+			addr := net.JoinHostPort(fields[3], fields[4])
+
+			sc.mutex.Lock()
+			sc.Client.rebind(addr)
+			sc.mutex.Unlock()
+		}
+
+		pubsub.Close()
+	}
+}
+
+func splitSpaces(s string) []string {
+	var fields []string
+	start := -1
+	for i, r := range s {
+		if r == ' ' {
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}